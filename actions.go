@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"golang.design/x/clipboard"
+)
+
+// openURL opens target in the OS's default browser.
+func openURL(target string) error {
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", "", target)
+	case "darwin":
+		cmd = exec.Command("open", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("could not open %q: %w", target, err)
+	}
+
+	// Reap the detached browser-launcher process once it exits, so
+	// repeated opens don't accumulate zombies.
+	go cmd.Wait()
+
+	return nil
+}
+
+var (
+	clipboardInitOnce sync.Once
+	clipboardInitErr  error
+)
+
+// copyToClipboard copies text to the OS clipboard.
+func copyToClipboard(text string) error {
+	clipboardInitOnce.Do(func() {
+		clipboardInitErr = clipboard.Init()
+	})
+	if clipboardInitErr != nil {
+		return fmt.Errorf("clipboard unavailable: %w", clipboardInitErr)
+	}
+
+	clipboard.Write(clipboard.FmtText, []byte(text))
+	return nil
+}
+
+// progressWriter reports bytes written so far to onProgress as they pass
+// through Write, so downloadFile can drive a progress row.
+type progressWriter struct {
+	written    int64
+	total      int64
+	onProgress func(written, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if w.onProgress != nil {
+		w.onProgress(w.written, w.total)
+	}
+	return len(p), nil
+}
+
+// downloadFile GETs downloadURL into destDir, reporting progress via
+// onProgress, and returns the path it wrote to.
+func downloadFile(downloadURL, destDir string, onProgress func(written, total int64)) (string, error) {
+	resp, err := http.Get(downloadURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s downloading %s", resp.Status, downloadURL)
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(destDir, downloadFilename(downloadURL))
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	progress := &progressWriter{total: resp.ContentLength, onProgress: onProgress}
+	if _, err := io.Copy(out, io.TeeReader(resp.Body, progress)); err != nil {
+		return "", err
+	}
+
+	return dest, nil
+}
+
+// downloadFilename derives a save-to filename from a download URL's path,
+// ignoring any query string (e.g. signed-URL parameters).
+func downloadFilename(downloadURL string) string {
+	name := downloadURL
+
+	if u, err := url.Parse(downloadURL); err == nil {
+		name = u.Path
+	}
+
+	name = filepath.Base(name)
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "download"
+	}
+	return name
+}
+
+// formatProgress renders a byte count, or a fraction of a known total.
+func formatProgress(written, total int64) string {
+	if total <= 0 {
+		return fmt.Sprintf("%d bytes", written)
+	}
+	return fmt.Sprintf("%d/%d bytes", written, total)
+}
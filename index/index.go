@@ -0,0 +1,362 @@
+// Package index implements a persistent inverted-index search subsystem
+// for the HTML chatlog corpus scraped from DiscordChatExporter exports.
+//
+// An Indexer walks a data folder of ".html" files, extracts the embedded
+// chatlog__embed-text blocks into Documents, and tokenizes their text
+// into a token -> []Posting inverted index. The result is an Index that
+// can be persisted to disk (gob) and reloaded, with a Manifest of file
+// mtimes so subsequent runs only re-parse changed or new files.
+package index
+
+import (
+	"context"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Document is a single chatlog embed extracted from an HTML export file.
+type Document struct {
+	ID           int
+	Name         string
+	SourceLink   string
+	DownloadLink string
+	File         string
+	Offset       int
+}
+
+// Posting records that a token occurs in a Document's Field, Freq times.
+type Posting struct {
+	DocID int
+	Field string
+	Freq  int
+}
+
+// Index is the on-disk inverted index: tokens map to postings, and
+// document IDs map back to the Document they were extracted from.
+type Index struct {
+	Tokens   map[string][]Posting
+	Docs     map[int]Document
+	Manifest map[string]time.Time // file path -> mtime, for incremental updates
+
+	nextID int
+}
+
+// SearchResult pairs a Document with its relevance score for ranking.
+type SearchResult struct {
+	Document
+	Score float64
+}
+
+var stopwords = map[string]bool{
+	"the": true, "a": true, "an": true, "and": true, "or": true, "of": true,
+	"to": true, "in": true, "is": true, "it": true, "for": true, "on": true,
+	"with": true, "by": true, "at": true, "from": true,
+}
+
+// tokenize case-folds text and splits it on runs of non-alphanumeric
+// characters, dropping stopwords.
+func tokenize(text string) []string {
+	raw := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var tokens []string
+	for _, tok := range raw {
+		if stopwords[tok] {
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// Load reads a previously saved Index from disk.
+func Load(indexPath string) (*Index, error) {
+	f, err := os.Open(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	idx := &Index{}
+	if err := gob.NewDecoder(f).Decode(idx); err != nil {
+		return nil, err
+	}
+
+	idx.rebuildNextID()
+	return idx, nil
+}
+
+// Save writes the Index to indexPath as a gob file.
+func (idx *Index) Save(indexPath string) error {
+	f, err := os.Create(indexPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return gob.NewEncoder(f).Encode(idx)
+}
+
+func (idx *Index) rebuildNextID() {
+	for id := range idx.Docs {
+		if id >= idx.nextID {
+			idx.nextID = id + 1
+		}
+	}
+}
+
+// addDocument assigns doc a fresh ID and indexes its fields.
+func (idx *Index) addDocument(doc Document) {
+	doc.ID = idx.nextID
+	idx.nextID++
+	idx.Docs[doc.ID] = doc
+
+	idx.indexField(doc.ID, "name", doc.Name)
+	idx.indexField(doc.ID, "source", doc.SourceLink)
+	idx.indexField(doc.ID, "download", doc.DownloadLink)
+}
+
+func (idx *Index) indexField(docID int, field, text string) {
+	freq := make(map[string]int)
+	for _, tok := range tokenize(text) {
+		freq[tok]++
+	}
+	for tok, count := range freq {
+		idx.Tokens[tok] = append(idx.Tokens[tok], Posting{DocID: docID, Field: field, Freq: count})
+	}
+}
+
+// removeFile drops every Document (and its postings) that was previously
+// extracted from filePath, ahead of re-indexing it.
+func (idx *Index) removeFile(filePath string) {
+	stale := make(map[int]bool)
+	for id, doc := range idx.Docs {
+		if doc.File == filePath {
+			stale[id] = true
+			delete(idx.Docs, id)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	for tok, postings := range idx.Tokens {
+		kept := postings[:0]
+		for _, p := range postings {
+			if !stale[p.DocID] {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.Tokens, tok)
+		} else {
+			idx.Tokens[tok] = kept
+		}
+	}
+}
+
+// Search ranks Documents against query with a simple term-frequency
+// score, best matches first.
+func (idx *Index) Search(query string) []SearchResult {
+	scores := make(map[int]float64)
+
+	for _, tok := range tokenize(query) {
+		for _, p := range idx.Tokens[tok] {
+			scores[p.DocID] += float64(p.Freq)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, SearchResult{Document: idx.Docs[id], Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Name < results[j].Name
+	})
+
+	return results
+}
+
+// Indexer walks a DataFolder of archive files and builds or incrementally
+// updates an Index persisted at IndexPath, using a bounded pool of
+// Workers to parse files concurrently.
+type Indexer struct {
+	DataFolder string
+	IndexPath  string
+	Workers    int
+}
+
+// NewIndexer returns an Indexer for the given data folder and index file,
+// defaulting Workers to GOMAXPROCS.
+func NewIndexer(dataFolder, indexPath string) *Indexer {
+	return &Indexer{DataFolder: dataFolder, IndexPath: indexPath, Workers: runtime.GOMAXPROCS(0)}
+}
+
+// Build performs a full walk of DataFolder into a fresh Index, ignoring
+// any mtime manifest. Use this for the -reindex flag.
+func (ix *Indexer) Build(ctx context.Context) (*Index, error) {
+	idx := &Index{
+		Tokens:   make(map[string][]Posting),
+		Docs:     make(map[int]Document),
+		Manifest: make(map[string]time.Time),
+	}
+	return idx, ix.update(ctx, idx, true)
+}
+
+// Update incrementally re-indexes files in DataFolder that are new or
+// have changed mtimes since idx.Manifest was recorded. Pass forceAll to
+// reindex every matching file regardless of mtime. Update stops early,
+// leaving idx with whatever finished so far, if ctx is canceled.
+func (ix *Indexer) Update(ctx context.Context, idx *Index, forceAll bool) error {
+	return ix.update(ctx, idx, forceAll)
+}
+
+type scanJob struct {
+	filePath string
+	mtime    time.Time
+}
+
+type scanResult struct {
+	filePath string
+	mtime    time.Time
+	docs     []Document
+	err      error
+}
+
+func (ix *Indexer) update(ctx context.Context, idx *Index, forceAll bool) error {
+	entries, err := os.ReadDir(ix.DataFolder)
+	if err != nil {
+		return err
+	}
+
+	var jobs []scanJob
+	for _, entry := range entries {
+		if entry.IsDir() || sourceFor(entry.Name()) == nil {
+			continue
+		}
+
+		filePath := filepath.Join(ix.DataFolder, entry.Name())
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		if !forceAll {
+			if last, ok := idx.Manifest[filePath]; ok && !info.ModTime().After(last) {
+				continue // unchanged since last index
+			}
+		}
+
+		jobs = append(jobs, scanJob{filePath: filePath, mtime: info.ModTime()})
+	}
+
+	workers := ix.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobChan := make(chan scanJob)
+	resultChan := make(chan scanResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				docs, err := extractDocuments(ctx, job.filePath)
+				select {
+				case resultChan <- scanResult{filePath: job.filePath, mtime: job.mtime, docs: docs, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobChan)
+		for _, job := range jobs {
+			select {
+			case jobChan <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	// idx is only ever mutated here, in the single goroutine draining
+	// resultChan, so no locking is needed around its maps.
+	for res := range resultChan {
+		if res.err != nil {
+			continue
+		}
+
+		idx.removeFile(res.filePath)
+		for _, doc := range res.docs {
+			idx.addDocument(doc)
+		}
+		idx.Manifest[res.filePath] = res.mtime
+	}
+
+	return ctx.Err()
+}
+
+// extractDocuments dispatches filePath to the first Source that matches
+// its name and collects every Document it extracts, stopping early if
+// ctx is canceled.
+func extractDocuments(ctx context.Context, filePath string) ([]Document, error) {
+	source := sourceFor(filePath)
+	if source == nil {
+		return nil, nil
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	docChan := make(chan Document)
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- source.Extract(f, filePath, docChan)
+		close(docChan)
+	}()
+
+	var docs []Document
+	for {
+		select {
+		case doc, ok := <-docChan:
+			if !ok {
+				return docs, <-errChan
+			}
+			docs = append(docs, doc)
+		case <-ctx.Done():
+			go func() {
+				for range docChan {
+				}
+			}()
+			return docs, ctx.Err()
+		}
+	}
+}
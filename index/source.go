@@ -0,0 +1,38 @@
+package index
+
+import "io"
+
+// Source adapts a single archive file format into Documents. The Indexer
+// dispatches each file in DataFolder to the first registered Source whose
+// Match returns true for that file's name.
+type Source interface {
+	// Match reports whether this Source can handle filename.
+	Match(filename string) bool
+	// Extract reads r and sends every Document it finds to out.
+	Extract(r io.Reader, file string, out chan<- Document) error
+}
+
+var sources []Source
+
+// RegisterSource adds s to the list of Sources consulted for each file,
+// in registration order; the first Source whose Match returns true wins.
+func RegisterSource(s Source) {
+	sources = append(sources, s)
+}
+
+func init() {
+	RegisterSource(discordChatlogSource{})
+	RegisterSource(openGraphSource{})
+	RegisterSource(jsonLinesSource{})
+}
+
+// sourceFor returns the first registered Source that matches filename, or
+// nil if none do.
+func sourceFor(filename string) Source {
+	for _, s := range sources {
+		if s.Match(filename) {
+			return s
+		}
+	}
+	return nil
+}
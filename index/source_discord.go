@@ -0,0 +1,139 @@
+package index
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// discordChatlogSource extracts Documents from DiscordChatExporter HTML
+// transcripts, reading the chatlog__embed-* classes it emits for each
+// rich embed.
+type discordChatlogSource struct{}
+
+func (discordChatlogSource) Match(filename string) bool {
+	return strings.HasSuffix(filename, ".html")
+}
+
+func (discordChatlogSource) Extract(r io.Reader, file string, out chan<- Document) error {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	collectEmbeds(doc, file, &offset, out)
+	return nil
+}
+
+func collectEmbeds(n *html.Node, file string, offset *int, out chan<- Document) {
+	if n.Type == html.ElementNode && n.Data == "div" && hasClass(n, "chatlog__embed-text") {
+		if d, ok := extractEmbed(n, file, *offset); ok {
+			out <- d
+		}
+		*offset++
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectEmbeds(c, file, offset, out)
+	}
+}
+
+// extractEmbed pulls the title and source/download links out of a single
+// chatlog__embed-text div.
+func extractEmbed(n *html.Node, file string, offset int) (Document, bool) {
+	doc := Document{File: file, Offset: offset}
+
+	for child := range n.ChildNodes() {
+		if hasClass(child, "chatlog__embed-title") {
+			for textChild := range child.ChildNodes() {
+				if hasClass(textChild, "chatlog__markdown chatlog__markdown-preserve") && textChild.FirstChild != nil {
+					doc.Name = textChild.FirstChild.Data
+				}
+			}
+		}
+
+		if hasClass(child, "chatlog__embed-description") || (hasClass(child, "chatlog__embed-fields") && doc.Name != "") {
+			linkContainer := findNodeRecursively(child, "chatlog__embed-fields")
+			if linkContainer == nil {
+				linkContainer = findNodeRecursively(child, "chatlog__embed-description")
+			}
+
+			for _, a := range findNodetypeRecursively(linkContainer, "a") {
+				if len(a.Attr) == 0 {
+					continue
+				}
+				href := a.Attr[0].Val
+
+				if hasResurivelyText(a, "Source") {
+					doc.SourceLink = href
+				}
+				if hasResurivelyText(a, "Download") {
+					doc.DownloadLink = href
+				}
+			}
+		}
+	}
+
+	if doc.Name == "" && doc.SourceLink == "" && doc.DownloadLink == "" {
+		return Document{}, false
+	}
+
+	return doc, true
+}
+
+func hasClass(n *html.Node, className string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "class" && attr.Val == className {
+			return true
+		}
+	}
+
+	return false
+}
+
+func findNodetypeRecursively(node *html.Node, nodeType string) []*html.Node {
+	if node == nil {
+		return nil
+	}
+
+	var result []*html.Node
+
+	if node.Type == html.ElementNode && node.Data == nodeType {
+		result = append(result, node)
+		return result
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		result = append(result, findNodetypeRecursively(child, nodeType)...)
+	}
+	return result
+}
+
+func hasResurivelyText(node *html.Node, searchText string) bool {
+	if node.Type == html.TextNode {
+		if strings.Contains(strings.ToLower(node.Data), strings.ToLower(searchText)) {
+			return true
+		}
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if hasResurivelyText(child, searchText) {
+			return true
+		}
+	}
+	return false
+}
+
+func findNodeRecursively(node *html.Node, className string) *html.Node {
+	if hasClass(node, className) {
+		return node
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if result := findNodeRecursively(child, className); result != nil {
+			return result
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package index
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// jsonLinesSource reads pre-extracted archives: one JSON object per line,
+// already shaped like a Document.
+type jsonLinesSource struct{}
+
+func (jsonLinesSource) Match(filename string) bool {
+	return strings.HasSuffix(filename, ".jsonl")
+}
+
+type jsonLineRecord struct {
+	Name         string `json:"name"`
+	SourceLink   string `json:"source_link"`
+	DownloadLink string `json:"download_link"`
+}
+
+func (jsonLinesSource) Extract(r io.Reader, file string, out chan<- Document) error {
+	scanner := bufio.NewScanner(r)
+
+	offset := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var rec jsonLineRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return err
+		}
+
+		out <- Document{
+			Name:         rec.Name,
+			SourceLink:   rec.SourceLink,
+			DownloadLink: rec.DownloadLink,
+			File:         file,
+			Offset:       offset,
+		}
+		offset++
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,70 @@
+package index
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// openGraphSource extracts a Document from arbitrary HTML pages using
+// their og:title/og:url meta tags plus the first anchor that looks like
+// a download link. It handles ".htm" archive dumps that are not
+// DiscordChatExporter transcripts.
+type openGraphSource struct{}
+
+func (openGraphSource) Match(filename string) bool {
+	return strings.HasSuffix(filename, ".htm")
+}
+
+func (openGraphSource) Extract(r io.Reader, file string, out chan<- Document) error {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return err
+	}
+
+	d := Document{File: file}
+	var downloadLink string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				switch metaAttr(n, "property") {
+				case "og:title":
+					d.Name = metaAttr(n, "content")
+				case "og:url":
+					d.SourceLink = metaAttr(n, "content")
+				}
+			case "a":
+				if downloadLink == "" && hasResurivelyText(n, "download") {
+					downloadLink = metaAttr(n, "href")
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	d.DownloadLink = downloadLink
+
+	if d.Name == "" && d.SourceLink == "" && d.DownloadLink == "" {
+		return nil
+	}
+
+	out <- d
+	return nil
+}
+
+func metaAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -1,16 +1,18 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"path"
-	"strings"
+	"path/filepath"
+	"runtime"
 
+	"github.com/MaxKruse/vrchive-explorer/index"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
-	"golang.org/x/net/html"
 )
 
 type SearchResult struct {
@@ -19,84 +21,205 @@ type SearchResult struct {
 	SourceLink   string
 }
 
+// resultBatchSize caps how many results ProcessInput buffers between
+// QueueUpdateDraw calls, so large result sets don't redraw the TUI once
+// per result.
+const resultBatchSize = 25
+
 var (
-	dataFolder string
-	searchText string
-	resultList *tview.TextView
+	dataFolder       string
+	searchText       string
+	resultTable      *tview.Table
+	statusBar        *tview.TextView
+	searchIndex      *index.Index
+	reindex          bool
+	httpAddr         string
+	workers          int
+	downloadsDir     string
+	activeCancel     context.CancelFunc
+	displayedResults []SearchResult
 )
 
-func searchFile(fileName string, searchText string, outputChannel chan SearchResult) {
-	log.Println("Searching file", fileName, ", for", searchText)
-	file, err := os.Open(fileName)
-	if err != nil {
-		fmt.Println("Error opening file:", err)
-		return
+const helpText = "[Enter] open source   [d] download   [y] copy link   [/] search   [Esc] cancel search"
+
+// runSearch queries the in-memory search index for query and returns
+// matches, best ranked first. It has no dependency on tview, so both the
+// TUI and the HTTP server in server.go can share it.
+func runSearch(query string) ([]SearchResult, error) {
+	if searchIndex == nil {
+		return nil, fmt.Errorf("search index is not initialized")
+	}
+
+	ranked := searchIndex.Search(query)
+	results := make([]SearchResult, 0, len(ranked))
+	for _, r := range ranked {
+		results = append(results, SearchResult{
+			Name:         r.Name,
+			DownloadLink: r.DownloadLink,
+			SourceLink:   r.SourceLink,
+		})
 	}
 
-	ParseHTML(file, searchText, outputChannel)
+	return results, nil
 }
 
-func Search(outputChannel chan SearchResult) {
-	searchTextCopy := searchText
+// Search runs runSearch for the current searchText and streams the
+// results into outputChannel, for consumption by the TUI. It stops
+// sending, and closes outputChannel, as soon as ctx is done.
+func Search(ctx context.Context, outputChannel chan SearchResult) {
+	defer close(outputChannel)
 
-	// find the html files
-	files, err := os.ReadDir(dataFolder)
+	results, err := runSearch(searchText)
 	if err != nil {
-		fmt.Println("Error reading directory:", err)
+		log.Printf("Error searching: %v", err)
 		return
 	}
 
-	// only use html files
-	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".html") {
-			// search in the file
-			searchFile(path.Join(dataFolder, file.Name()), searchTextCopy, outputChannel)
+	for _, result := range results {
+		select {
+		case outputChannel <- result:
+		case <-ctx.Done():
+			return
 		}
 	}
-
-	close(outputChannel) // Close the channel after sending all results
 }
 
+// ProcessInput cancels any in-flight search, starts a new one for the
+// current searchText, and streams its results into resultTable in
+// batches to keep QueueUpdateDraw churn down on large result sets.
 func ProcessInput(app *tview.Application, inputField *tview.InputField) {
+	if activeCancel != nil {
+		activeCancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	activeCancel = cancel
+
 	// Clear the results and set initial text
-	resultList.Clear()
-	resultList.Write([]byte(fmt.Sprintf("Searching: '%s' ...", searchText)))
+	resultTable.Clear()
+	resultTable.SetCell(0, 0, tview.NewTableCell(fmt.Sprintf("Searching: '%s' ...", searchText)))
+	displayedResults = nil
 
 	resultChan := make(chan SearchResult)
-	go Search(resultChan)
+	go Search(ctx, resultChan)
 
 	startedSearching := true
+	batch := make([]SearchResult, 0, resultBatchSize)
 
-	// Use a goroutine to handle results from the channel
-	go func() {
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		toDraw := batch
+		batch = make([]SearchResult, 0, resultBatchSize)
 
-		for {
+		app.QueueUpdateDraw(func() {
+			if startedSearching {
+				resultTable.Clear()
+				startedSearching = false
+			}
 
-			result, ok := <-resultChan
-			if !ok {
-				break
+			for _, result := range toDraw {
+				row := resultTable.GetRowCount()
+				resultTable.SetCell(row, 0, tview.NewTableCell(result.Name).SetExpansion(2))
+				resultTable.SetCell(row, 1, tview.NewTableCell(result.SourceLink).SetExpansion(1))
+				resultTable.SetCell(row, 2, tview.NewTableCell(result.DownloadLink).SetExpansion(1))
+				displayedResults = append(displayedResults, result)
 			}
+		})
+	}
 
-			// Use app.QueueUpdateDraw to update the UI safely
-			app.QueueUpdateDraw(func() {
-				if startedSearching {
-					resultList.Clear()
-					startedSearching = false
+	// Use a goroutine to handle results from the channel
+	go func() {
+		for {
+			select {
+			case result, ok := <-resultChan:
+				if !ok {
+					flush()
+					if ctx.Err() == nil {
+						// Say when we're done
+						app.QueueUpdateDraw(func() {
+							resultTable.SetTitle(fmt.Sprintf(" Results (%d) ", len(displayedResults)))
+
+							inputField.SetText("")
+							inputField.SetDisabled(false)
+
+							// Hand focus to the results so Enter/d/y work
+							// immediately; '/' sends focus back to search.
+							if len(displayedResults) > 0 {
+								resultTable.Select(0, 0)
+								app.SetFocus(resultTable)
+							}
+						})
+					}
+					return
 				}
 
-				resultStr := result.Name + "\t" + result.SourceLink + "\t" + result.DownloadLink + "\n"
-				resultList.Write([]byte(resultStr))
-			})
+				batch = append(batch, result)
+				if len(batch) >= resultBatchSize {
+					flush()
+				}
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+}
 
-		// Say when we're done
-		app.QueueUpdateDraw(func() {
-			resultList.Write([]byte("Done!"))
+// resultAtRow returns the SearchResult displayed at the given table row,
+// if any.
+func resultAtRow(row int) (SearchResult, bool) {
+	if row < 0 || row >= len(displayedResults) {
+		return SearchResult{}, false
+	}
+	return displayedResults[row], true
+}
 
-			inputField.SetText("")
-			inputField.SetDisabled(false)
+// openResultLink opens a result's SourceLink in the OS's default browser.
+func openResultLink(r SearchResult) {
+	if r.SourceLink == "" {
+		return
+	}
+	if err := openURL(r.SourceLink); err != nil {
+		log.Printf("Error opening link: %v", err)
+	}
+}
+
+// copyResultLink copies a result's link to the OS clipboard, preferring
+// the source link and falling back to the download link.
+func copyResultLink(r SearchResult) {
+	link := r.SourceLink
+	if link == "" {
+		link = r.DownloadLink
+	}
+	if link == "" {
+		return
+	}
+	if err := copyToClipboard(link); err != nil {
+		log.Printf("Error copying link to clipboard: %v", err)
+	}
+}
+
+// startDownload fetches a result's DownloadLink into downloadsDir,
+// reporting progress and the final outcome on statusBar.
+func startDownload(app *tview.Application, r SearchResult) {
+	if r.DownloadLink == "" {
+		return
+	}
+
+	dest, err := downloadFile(r.DownloadLink, downloadsDir, func(written, total int64) {
+		app.QueueUpdateDraw(func() {
+			statusBar.SetText(fmt.Sprintf("Downloading %s: %s", r.Name, formatProgress(written, total)))
 		})
-	}()
+	})
+
+	app.QueueUpdateDraw(func() {
+		if err != nil {
+			statusBar.SetText(fmt.Sprintf("Download failed: %v", err))
+			return
+		}
+		statusBar.SetText(fmt.Sprintf("Downloaded %s -> %s", r.Name, dest))
+	})
 }
 
 func setupLogger(logFileName string) error {
@@ -112,6 +235,27 @@ func setupLogger(logFileName string) error {
 	return nil
 }
 
+// loadSearchIndex loads the on-disk index for dataFolder, building it from
+// scratch if it doesn't exist yet or forceRebuild is set, and otherwise
+// incrementally re-indexing only the files that changed since last run.
+// Scanning is spread across idxr.Workers goroutines and stops early if
+// ctx is canceled.
+func loadSearchIndex(ctx context.Context, indexPath string, forceRebuild bool) (*index.Index, error) {
+	idxr := index.NewIndexer(dataFolder, indexPath)
+	idxr.Workers = workers
+
+	if !forceRebuild {
+		if idx, err := index.Load(indexPath); err == nil {
+			if err := idxr.Update(ctx, idx, false); err != nil {
+				return nil, err
+			}
+			return idx, nil
+		}
+	}
+
+	return idxr.Build(ctx)
+}
+
 func main() {
 	// we need arguments for:
 	// 1. the data folder of the html files, which defaults to ./data
@@ -119,8 +263,13 @@ func main() {
 
 	// flags/arguments:
 	// 1. data folder
+	// 2. whether to force a full index rebuild
 
 	flag.StringVar(&dataFolder, "data", "./data", "The folder containing the html files to search.")
+	flag.BoolVar(&reindex, "reindex", false, "Force a full rebuild of the search index instead of an incremental update.")
+	flag.StringVar(&httpAddr, "http", "", "If set (e.g. ':8080'), serve search over HTTP instead of running the TUI.")
+	flag.IntVar(&workers, "workers", runtime.GOMAXPROCS(0), "Number of concurrent workers used to scan archive files when building or updating the search index.")
+	flag.StringVar(&downloadsDir, "downloads", "./downloads", "The folder downloaded files are saved to.")
 
 	flag.Parse()
 
@@ -130,6 +279,26 @@ func main() {
 		return
 	}
 
+	indexPath := filepath.Join(dataFolder, ".vrchive-index.gob")
+
+	searchIndex, err = loadSearchIndex(context.Background(), indexPath, reindex)
+	if err != nil {
+		fmt.Println("Error building search index:", err)
+		return
+	}
+
+	if err := searchIndex.Save(indexPath); err != nil {
+		log.Printf("Error saving search index: %v", err)
+	}
+
+	if httpAddr != "" {
+		log.Printf("Serving search over HTTP on %s", httpAddr)
+		if err := http.ListenAndServe(httpAddr, newSearchServer()); err != nil {
+			fmt.Println("Error running HTTP server:", err)
+		}
+		return
+	}
+
 	app := tview.NewApplication()
 
 	defer func() {
@@ -156,18 +325,57 @@ func main() {
 		inputField.SetDisabled(true)
 		ProcessInput(app, inputField)
 	})
+	inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc && activeCancel != nil {
+			activeCancel()
+			return nil
+		}
+		return event
+	})
+
+	// Create a table to display results and bind result actions to it
+	resultTable = tview.NewTable().SetSelectable(true, false)
+	resultTable.SetBorder(true)
+	resultTable.SetTitle(" Results ")
+	resultTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		row, _ := resultTable.GetSelection()
+		result, ok := resultAtRow(row)
+
+		switch {
+		case event.Key() == tcell.KeyEnter:
+			if ok {
+				openResultLink(result)
+			}
+			return nil
+		case event.Rune() == 'd':
+			if ok {
+				go startDownload(app, result)
+			}
+			return nil
+		case event.Rune() == 'y':
+			if ok {
+				copyResultLink(result)
+			}
+			return nil
+		case event.Rune() == '/':
+			app.SetFocus(inputField)
+			return nil
+		}
+
+		return event
+	})
 
-	// Create a scrollable list to display results
-	resultList = tview.NewTextView()
-	resultList.SetBorder(true)
-	resultList.SetTextAlign(tview.AlignLeft)
+	// Status/help bar showing the active keybindings
+	statusBar = tview.NewTextView().
+		SetText(helpText)
 
 	// Layout setup with a flexbox
 	layout := tview.NewFlex().
-		SetDirection(tview.FlexRow).      // Vertical layout
-		AddItem(headerText, 2, 1, false). // Header occupies 3 rows
-		AddItem(inputField, 3, 1, true).  // Input field occupies 3 rows
-		AddItem(resultList, 0, 3, false)  // Result list occupies the remaining space
+		SetDirection(tview.FlexRow).       // Vertical layout
+		AddItem(headerText, 2, 1, false).  // Header occupies 3 rows
+		AddItem(inputField, 3, 1, true).   // Input field occupies 3 rows
+		AddItem(resultTable, 0, 3, false). // Result table occupies the remaining space
+		AddItem(statusBar, 1, 1, false)    // Status/help bar occupies 1 row
 
 	log.Printf("Setup application and running it")
 
@@ -177,165 +385,3 @@ func main() {
 	}
 
 }
-
-// ParseHTML reads the HTML file and searches for matching content
-func ParseHTML(r *os.File, searchText string, outChannel chan SearchResult) {
-	// Read the content of the file
-	content, err := os.ReadFile(r.Name())
-	if err != nil {
-		log.Printf("Error reading file %s: %v", r.Name(), err)
-		return
-	}
-
-	// Parse the HTML content
-	doc, err := html.Parse(strings.NewReader(string(content)))
-	if err != nil {
-		log.Printf("Error parsing HTML in file %s: %v", r.Name(), err)
-		return
-	}
-
-	processAllItems(doc, outChannel)
-}
-
-func hasSearchText(text string, searchText string) bool {
-	// do a case insensitive search
-	return strings.Contains(strings.ToLower(text), strings.ToLower(searchText))
-}
-
-func hasClass(n *html.Node, className string) bool {
-	for _, attr := range n.Attr {
-		if attr.Key == "class" && attr.Val == className {
-			return true
-		}
-	}
-
-	return false
-}
-
-func isAnchor(n *html.Node) bool {
-	return n.Type == html.ElementNode && n.Data == "a"
-}
-
-func isEm(n *html.Node) bool {
-	return n.Type == html.ElementNode && n.Data == "em"
-}
-
-func isStrong(n *html.Node) bool {
-	return n.Type == html.ElementNode && n.Data == "strong"
-}
-
-func processAllItems(doc *html.Node, outChannel chan SearchResult) {
-	if doc.Type == html.ElementNode && doc.Data == "div" {
-		if hasClass(doc, "chatlog__embed-text") {
-			processNode(doc, outChannel)
-		}
-	}
-
-	for c := doc.FirstChild; c != nil; c = c.NextSibling {
-		processAllItems(c, outChannel)
-	}
-}
-
-func findNodetypeRecursively(node *html.Node, nodeType string) []*html.Node {
-	var result []*html.Node
-
-	if node.Type == html.ElementNode && node.Data == nodeType {
-		result = append(result, node)
-		return result
-		// return node
-	}
-	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		result = append(result, findNodetypeRecursively(child, nodeType)...)
-	}
-	return result
-}
-
-func hasResurivelyText(node *html.Node, searchText string) bool {
-	if node.Type == html.TextNode {
-		if hasSearchText(node.Data, searchText) {
-			return true
-		}
-	}
-
-	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		if hasResurivelyText(child, searchText) {
-			return true
-		}
-	}
-	return false
-}
-
-func findNodeRecursively(node *html.Node, className string) *html.Node {
-	if hasClass(node, className) {
-		return node
-	}
-
-	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		if result := findNodeRecursively(child, className); result != nil {
-			return result
-		}
-	}
-	return nil
-}
-
-func processNode(doc *html.Node, outChannel chan SearchResult) {
-	Result := SearchResult{}
-
-	for child := range doc.ChildNodes() {
-		if hasClass(child, "chatlog__embed-title") {
-			// this has the title. in this div, there is another div that then has the text
-			for textChild := range child.ChildNodes() {
-				if hasClass(textChild, "chatlog__markdown chatlog__markdown-preserve") {
-					textData := textChild.FirstChild.Data
-					Result.Name = textData
-				}
-			}
-		}
-
-		log.Printf("Found title: %s", Result.Name)
-
-		if hasClass(child, "chatlog__embed-description") || hasClass(child, "chatlog__embed-fields") && Result.Name != "" {
-			// this has the description. its nested as hell, so we go slowly through it...
-
-			linkContainer := findNodeRecursively(child, "chatlog__embed-fields")
-			if linkContainer == nil {
-				linkContainer = findNodeRecursively(child, "chatlog__embed-description")
-			}
-
-			log.Printf("Found linkContainer: %v", linkContainer)
-
-			anchorTags := findNodetypeRecursively(linkContainer, "a")
-
-			for _, aContainer := range anchorTags {
-				hrefData := aContainer.Attr[0].Val
-				log.Printf("Found Download link: %s on element %s", hrefData, aContainer.Data)
-
-				// if the current container has a nested child anywhere that says Source, assign the source link
-				if hasResurivelyText(aContainer, "Source") {
-					Result.SourceLink = hrefData
-				}
-
-				// if the current container has a nested child anywhere that says Download, assign the download link
-				if hasResurivelyText(aContainer, "Download") {
-					Result.DownloadLink = hrefData
-				}
-
-			}
-		}
-
-		log.Printf("Found source: %s", Result.SourceLink)
-		log.Printf("Found Download: %s", Result.DownloadLink)
-
-	}
-
-	// if the name, source or download dont contain the search text, we are done
-	if hasSearchText(Result.Name, searchText) || hasSearchText(Result.SourceLink, searchText) || hasSearchText(Result.DownloadLink, searchText) {
-		log.Printf("Result found for %s", searchText)
-		log.Printf("Result: %v", Result)
-		outChannel <- Result
-		return
-	} else {
-		log.Printf("Cant find result for %s", searchText)
-		log.Printf("Result: %v", Result)
-	}
-}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const resultsPerPage = 20
+
+var resultsPageTemplate = template.Must(template.New("results").Parse(`<!DOCTYPE html>
+<html>
+<head><title>VRChive Search: {{.Query}}</title></head>
+<body>
+<h1>Search: {{.Query}}</h1>
+<ul>
+{{range .Results}}<li><a href="{{.SourceLink}}">{{.Name}}</a> &mdash; <a href="{{.DownloadLink}}">download</a></li>
+{{end}}
+</ul>
+</body>
+</html>
+`))
+
+// searchHandler serves GET /search?q=...&page=... as JSON by default, or
+// as a minimal HTML results page when the client asks for text/html.
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	results, err := runSearch(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	results = paginate(results, page, resultsPerPage)
+
+	if acceptsHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := resultsPageTemplate.Execute(w, struct {
+			Query   string
+			Results []SearchResult
+		}{Query: query, Results: results}); err != nil {
+			log.Printf("Error rendering results template: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Printf("Error encoding search results: %v", err)
+	}
+}
+
+func paginate(results []SearchResult, page, perPage int) []SearchResult {
+	start := (page - 1) * perPage
+	if start > len(results) {
+		start = len(results)
+	}
+	end := start + perPage
+	if end > len(results) {
+		end = len(results)
+	}
+	return results[start:end]
+}
+
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// opensearchHandler describes this search engine so browsers can add it
+// as a search provider, per the OpenSearch description format.
+func opensearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/opensearchdescription+xml")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<OpenSearchDescription xmlns="http://a9.com/-/spec/opensearch/1.1/">
+  <ShortName>VRChive Explorer</ShortName>
+  <Description>Search the VRChive chatlog archive</Description>
+  <InputEncoding>UTF-8</InputEncoding>
+  <Url type="text/html" template="http://%[1]s/search?q={searchTerms}&amp;page={startPage?}"/>
+  <Url type="application/json" template="http://%[1]s/search?q={searchTerms}&amp;page={startPage?}"/>
+</OpenSearchDescription>
+`, r.Host)
+}
+
+// newSearchServer builds the http.Handler that serves search over HTTP.
+func newSearchServer() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", searchHandler)
+	mux.HandleFunc("/opensearch.xml", opensearchHandler)
+	return mux
+}